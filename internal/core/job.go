@@ -0,0 +1,44 @@
+// Package core defines the types shared between the server and the
+// workers over the gRPC wire protocol.
+package core
+
+// Status represents the lifecycle state of a Job.
+type Status uint8
+
+// Job statuses.
+const (
+	StatusUnknown Status = iota
+	StatusQueued
+	StatusRunning
+	StatusPassed
+	StatusFailed
+	StatusCanceled
+)
+
+// Job is a single unit of work sent to a worker for execution.
+type Job struct {
+	ID            uint
+	BuildID       uint
+	Commands      string
+	Image         string
+	Env           []string
+	URL           string
+	ProviderName  string
+	ProviderURL   string
+	ProviderToken string
+	CommitSHA     string
+	RepoName      string
+	Priority      uint16
+	Status        Status
+
+	// RepoID and UserID identify the owning repo/user for fair-share
+	// scheduling across queues.
+	RepoID uint
+	UserID uint
+
+	// CPU and Memory are the resource requests parsed from the job's
+	// `.abstruse.yml` matrix entry (millicores and bytes respectively).
+	// Zero means "no preference".
+	CPU    int64
+	Memory int64
+}
@@ -0,0 +1,48 @@
+package logger
+
+import "strings"
+
+const maskedSecret = "********"
+
+// LineWriter wraps an io.Writer used to persist or broadcast job log
+// output and replaces any occurrence of a registered secret value with
+// a mask before it reaches the underlying writer. Secrets must never
+// reach persisted logs or the websocket broadcast in clear text.
+type LineWriter struct {
+	w       Writer
+	secrets []string
+}
+
+// Writer is the subset of io.Writer LineWriter wraps, kept minimal so
+// callers (websocket broadcaster, log persistence) don't need to
+// depend on this package beyond what they use.
+type Writer interface {
+	Write(p []byte) (int, error)
+}
+
+// NewLineWriter returns a LineWriter that masks every occurrence of
+// secrets in data written through it before forwarding to w.
+func NewLineWriter(w Writer, secrets ...string) *LineWriter {
+	filtered := secrets[:0:0]
+	for _, s := range secrets {
+		if s != "" {
+			filtered = append(filtered, s)
+		}
+	}
+	return &LineWriter{w: w, secrets: filtered}
+}
+
+// Write masks registered secrets in p and forwards the result to the
+// wrapped writer. The returned byte count always matches len(p) so
+// callers relying on io.Writer semantics aren't confused by the masked
+// length differing from the input length.
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	line := string(p)
+	for _, s := range lw.secrets {
+		line = strings.ReplaceAll(line, s, maskedSecret)
+	}
+	if _, err := lw.w.Write([]byte(line)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
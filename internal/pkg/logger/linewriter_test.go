@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineWriterMasksSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	lw := NewLineWriter(&buf, "s3cr3t", "t0ken")
+
+	n, err := lw.Write([]byte("login with s3cr3t and t0ken please"))
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if n != len("login with s3cr3t and t0ken please") {
+		t.Errorf("Write returned n=%d, want len(p)", n)
+	}
+
+	want := "login with ******** and ******** please"
+	if buf.String() != want {
+		t.Errorf("Write masked output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLineWriterIgnoresEmptySecrets(t *testing.T) {
+	var buf bytes.Buffer
+	lw := NewLineWriter(&buf, "", "real")
+
+	if _, err := lw.Write([]byte("nothing to mask here, but real is")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	want := "nothing to mask here, but ******** is"
+	if buf.String() != want {
+		t.Errorf("Write output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLineWriterPassthroughWithNoSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	lw := NewLineWriter(&buf)
+
+	line := "plain log line"
+	if _, err := lw.Write([]byte(line)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if buf.String() != line {
+		t.Errorf("Write output = %q, want %q", buf.String(), line)
+	}
+}
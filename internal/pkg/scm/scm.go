@@ -0,0 +1,42 @@
+// Package scm provides a provider-agnostic interface over the source
+// control management systems abstruse integrates with (GitHub, GitLab,
+// Bitbucket, Gitea...).
+package scm
+
+import "context"
+
+// Commit describes a single commit as reported by the SCM provider.
+type Commit struct {
+	Sha       string
+	Message   string
+	Author    User
+	Committer User
+}
+
+// User describes the author or committer of a Commit.
+type User struct {
+	Login  string
+	Name   string
+	Email  string
+	Avatar string
+}
+
+// Content is a single file fetched from the provider at a given ref.
+type Content struct {
+	Data []byte
+}
+
+// SCM is implemented by every supported source control provider.
+type SCM interface {
+	LastCommit(repo, branch string) (*Commit, error)
+	FindCommit(repo, sha string) (*Commit, error)
+	FindContent(repo, sha, path string) (*Content, error)
+	// Compare returns the set of file paths changed between base and
+	// head, used to evaluate path filters before scheduling a build.
+	Compare(repo, base, head string) ([]string, error)
+}
+
+// NewSCM returns the SCM implementation for the given provider name.
+func NewSCM(ctx context.Context, name, url, accessToken string) (SCM, error) {
+	panic("not implemented")
+}
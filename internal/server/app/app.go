@@ -5,6 +5,7 @@ import (
 
 	"github.com/jkuri/abstruse/internal/server/db/repository"
 	"github.com/jkuri/abstruse/internal/server/options"
+	"github.com/jkuri/abstruse/internal/server/secret"
 	"github.com/jkuri/abstruse/internal/server/websocket"
 	"go.etcd.io/etcd/clientv3"
 	"go.uber.org/zap"
@@ -22,22 +23,26 @@ type App struct {
 	scheduler *scheduler
 	errch     chan error
 
-	buildRepository repository.BuildRepository
-	jobRepository   repository.JobRepository
-	repoRepository  repository.RepoRepository
+	buildRepository  repository.BuildRepository
+	jobRepository    repository.JobRepository
+	repoRepository   repository.RepoRepository
+	secretRepository secret.SecretRepository
+	cronRepository   repository.CronRepository
 }
 
 // NewApp returns new instance of App.
-func NewApp(opts *options.Options, ws *websocket.App, rr repository.RepoRepository, jr repository.JobRepository, br repository.BuildRepository, logger *zap.Logger) (*App, error) {
+func NewApp(opts *options.Options, ws *websocket.App, rr repository.RepoRepository, jr repository.JobRepository, br repository.BuildRepository, sr secret.SecretRepository, cr repository.CronRepository, logger *zap.Logger) (*App, error) {
 	app := &App{
-		opts:            opts,
-		Workers:         make(map[string]*worker),
-		ws:              ws,
-		buildRepository: br,
-		jobRepository:   jr,
-		repoRepository:  rr,
-		logger:          logger.With(zap.String("type", "app")).Sugar(),
-		errch:           make(chan error),
+		opts:             opts,
+		Workers:          make(map[string]*worker),
+		ws:               ws,
+		buildRepository:  br,
+		jobRepository:    jr,
+		repoRepository:   rr,
+		secretRepository: sr,
+		cronRepository:   cr,
+		logger:           logger.With(zap.String("type", "app")).Sugar(),
+		errch:            make(chan error),
 	}
 
 	return app, nil
@@ -60,6 +65,12 @@ func (app *App) Start(client *clientv3.Client) error {
 		}
 	}()
 
+	go func() {
+		if err := newCronManager(app).run(); err != nil {
+			app.errch <- err
+		}
+	}()
+
 	return <-app.errch
 }
 
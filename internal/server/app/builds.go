@@ -9,24 +9,66 @@ import (
 	"github.com/jkuri/abstruse/internal/pkg/scm"
 	"github.com/jkuri/abstruse/internal/server/db/model"
 	"github.com/jkuri/abstruse/internal/server/parser"
+	"github.com/jkuri/abstruse/internal/server/secret"
 	jsoniter "github.com/json-iterator/go"
 )
 
-// TriggerBuild temp func.
-func (app *App) TriggerBuild(repoID, userID uint) error {
-	repo, err := app.repoRepository.Find(repoID, userID)
+// TriggerBuildOptions carries everything needed to create a build
+// across the different ways one can be triggered: a push or
+// pull_request webhook, a tag, a cron schedule, or a manual/deploy
+// request from the UI or API. Sharing one struct lets all of those
+// invocations funnel through the same TriggerBuild code path.
+type TriggerBuildOptions struct {
+	RepoID uint
+	UserID uint
+	Event  string
+	Branch string
+	// Ref is the full git ref the event fired on (e.g. refs/heads/main,
+	// refs/tags/v1.0.0), when known. Optional.
+	Ref string
+	// Commit pins the build to a specific commit SHA instead of the
+	// latest commit on Branch. Optional.
+	Commit string
+	// Base is the commit the push/pull_request is based on (the
+	// webhook's "before" SHA). Used to diff changed paths for `when:
+	// path` filters; left empty it just means path filters fail open.
+	Base string
+	// Deploy is the deploy target name, only set when Event is
+	// EventDeploy.
+	Deploy string
+	// Parent is the ID of the build this one restarts, if any.
+	Parent *uint
+}
+
+// TriggerBuild creates and schedules a new build for the repository
+// and event described by opts.
+func (app *App) TriggerBuild(opts TriggerBuildOptions) error {
+	repo, err := app.repoRepository.Find(opts.RepoID, opts.UserID)
 	if err != nil {
 		return err
 	}
-	scm, err := scm.NewSCM(context.Background(), repo.Provider.Name, repo.Provider.URL, repo.Provider.AccessToken)
+	provider, err := scm.NewSCM(context.Background(), repo.Provider.Name, repo.Provider.URL, repo.Provider.AccessToken)
 	if err != nil {
 		return err
 	}
-	commit, err := scm.LastCommit(repo.FullName, repo.DefaultBranch)
+	branch := opts.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	var commit *scm.Commit
+	if opts.Commit != "" {
+		commit, err = provider.FindCommit(repo.FullName, opts.Commit)
+	} else {
+		commit, err = provider.LastCommit(repo.FullName, branch)
+	}
 	if err != nil {
 		return err
 	}
-	content, err := scm.FindContent(repo.FullName, commit.Sha, ".abstruse.yml")
+	if parser.HasSkipDirective(commit.Message) {
+		app.logger.Infof("skipping build for %s@%s: skip-ci directive in commit message", repo.FullName, commit.Sha)
+		return nil
+	}
+	content, err := provider.FindContent(repo.FullName, commit.Sha, ".abstruse.yml")
 	if err != nil {
 		return err
 	}
@@ -39,8 +81,26 @@ func (app *App) TriggerBuild(repoID, userID uint) error {
 		return err
 	}
 
+	event := opts.Event
+	if event == "" {
+		event = model.EventManual
+	}
+
+	var changedFiles []string
+	if config.HasPathFilters() && opts.Base != "" {
+		changedFiles, err = provider.Compare(repo.FullName, opts.Base, commit.Sha)
+		if err != nil {
+			return err
+		}
+	}
+	if !config.Parsed.When.Matches(branch, event, changedFiles) {
+		app.logger.Infof("skipping build for %s@%s: excluded by top-level when filter", repo.FullName, commit.Sha)
+		return nil
+	}
+
 	buildModel := model.Build{
-		Branch:          repo.DefaultBranch,
+		Event:           event,
+		Branch:          branch,
 		Commit:          commit.Sha,
 		CommitMessage:   commit.Message,
 		Config:          string(content.Data),
@@ -53,6 +113,8 @@ func (app *App) TriggerBuild(repoID, userID uint) error {
 		CommitterEmail:  commit.Committer.Email,
 		CommitterAvatar: commit.Committer.Avatar,
 		RepositoryID:    repo.ID,
+		UserID:          opts.UserID,
+		ParentID:        opts.Parent,
 		StartTime:       func(t time.Time) *time.Time { return &t }(time.Now()),
 	}
 	build, err := app.buildRepository.Create(buildModel)
@@ -60,18 +122,23 @@ func (app *App) TriggerBuild(repoID, userID uint) error {
 		return err
 	}
 
-	for _, env := range config.Env {
+	for _, entry := range config.Parsed.Matrix {
+		if !entry.When.Matches(branch, event, changedFiles) {
+			continue
+		}
 		jobModel := &model.Job{
 			Image:    config.Parsed.Image,
 			Commands: string(commandsJSON),
-			Env:      env,
+			Env:      entry.Env,
 			BuildID:  build.ID,
+			CPU:      config.Parsed.Resources.CPU,
+			Memory:   config.Parsed.Resources.Memory,
 		}
 		job, err := app.jobRepository.Create(jobModel)
 		if err != nil {
 			return err
 		}
-		if err := app.scheduleJob(job, repo, commit.Sha); err != nil {
+		if err := app.scheduleJob(job, repo, commit.Sha, event, opts.UserID); err != nil {
 			return err
 		}
 	}
@@ -97,7 +164,7 @@ func (app *App) RestartJob(jobID uint) error {
 	if err != nil {
 		return err
 	}
-	return app.scheduleJob(job, job.Build.Repository, job.Build.Commit)
+	return app.scheduleJob(job, job.Build.Repository, job.Build.Commit, job.Build.Event, job.Build.UserID)
 }
 
 // StopBuild stops the build and related jobs.
@@ -120,45 +187,95 @@ func (app *App) StopBuild(buildID uint) (model.Build, error) {
 	return build, err
 }
 
-// RestartBuild stops the current build related jobs if any, then start them again.
+// RestartBuild stops the current build related jobs if any, then
+// creates a new build for the same repository and commit with Parent
+// set to buildID, so the restart lineage stays queryable.
 func (app *App) RestartBuild(buildID uint) error {
 	build, err := app.StopBuild(buildID)
 	if err != nil {
 		return err
 	}
-	build.StartTime = nil
-	build.EndTime = nil
-	if build, err = app.buildRepository.Update(build); err != nil {
+
+	restarted := model.Build{
+		Event:           build.Event,
+		Branch:          build.Branch,
+		Commit:          build.Commit,
+		CommitMessage:   build.CommitMessage,
+		Config:          build.Config,
+		AuthorLogin:     build.AuthorLogin,
+		AuthorName:      build.AuthorName,
+		AuthorEmail:     build.AuthorEmail,
+		AuthorAvatar:    build.AuthorAvatar,
+		CommitterLogin:  build.CommitterLogin,
+		CommitterName:   build.CommitterName,
+		CommitterEmail:  build.CommitterEmail,
+		CommitterAvatar: build.CommitterAvatar,
+		RepositoryID:    build.RepositoryID,
+		UserID:          build.UserID,
+		ParentID:        &build.ID,
+		StartTime:       func(t time.Time) *time.Time { return &t }(time.Now()),
+	}
+	newBuild, err := app.buildRepository.Create(restarted)
+	if err != nil {
 		return err
 	}
+
 	var wg sync.WaitGroup
 	wg.Add(len(build.Jobs))
 	for _, job := range build.Jobs {
 		go func(job *model.Job) {
-			if err := app.scheduleJob(job, build.Repository, build.Commit); err != nil {
-				app.logger.Debugf("error scheduling job %d: %v", job.ID, err)
+			jobModel := &model.Job{
+				Image:    job.Image,
+				Commands: job.Commands,
+				Env:      job.Env,
+				BuildID:  newBuild.ID,
+				CPU:      job.CPU,
+				Memory:   job.Memory,
+			}
+			newJob, err := app.jobRepository.Create(jobModel)
+			if err != nil {
+				app.logger.Debugf("error creating job for restarted build %d: %v", newBuild.ID, err)
+				wg.Done()
+				return
+			}
+			if err := app.scheduleJob(newJob, build.Repository, build.Commit, build.Event, build.UserID); err != nil {
+				app.logger.Debugf("error scheduling job %d: %v", newJob.ID, err)
 			}
 			wg.Done()
 		}(job)
 	}
 	wg.Wait()
-	return nil
+
+	return app.broadcastNewBuild(newBuild.ID)
 }
 
-func (app *App) scheduleJob(job *model.Job, repo *model.Repository, commitSHA string) error {
+func (app *App) scheduleJob(job *model.Job, repo *model.Repository, commitSHA, event string, userID uint) error {
+	env := job.Env
+	if app.secretRepository != nil {
+		secrets, err := app.secretRepository.FindForRepo(repo.ID)
+		if err != nil {
+			return err
+		}
+		env = append(env, secret.Env(secret.Match(secrets, event, job.Image))...)
+	}
+
 	j := &core.Job{
 		ID:            job.ID,
 		BuildID:       job.BuildID,
 		Commands:      job.Commands,
 		Image:         job.Image,
-		Env:           job.Env,
+		Env:           env,
 		URL:           repo.URL,
 		ProviderName:  repo.Provider.Name,
 		ProviderURL:   repo.Provider.URL,
 		ProviderToken: repo.Provider.AccessToken,
 		CommitSHA:     commitSHA,
 		RepoName:      repo.FullName,
-		Priority:      uint16(1000),
+		RepoID:        repo.ID,
+		UserID:        userID,
+		CPU:           job.CPU,
+		Memory:        job.Memory,
+		Priority:      jobPriority(event, repo.Weight),
 		Status:        core.StatusUnknown,
 	}
 	app.scheduler.Schedule(j)
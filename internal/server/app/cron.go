@@ -0,0 +1,79 @@
+package app
+
+import (
+	"time"
+
+	"github.com/jkuri/abstruse/internal/server/db/model"
+	"github.com/robfig/cron/v3"
+)
+
+// cronTickInterval is how often the cron manager wakes up to check
+// for due entries. Entries are never missed by more than this much.
+const cronTickInterval = 30 * time.Second
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// cronManager periodically triggers builds for due model.Cron entries.
+type cronManager struct {
+	app *App
+}
+
+func newCronManager(app *App) *cronManager {
+	return &cronManager{app: app}
+}
+
+// run blocks, waking up every cronTickInterval to trigger due builds.
+func (m *cronManager) run() error {
+	ticker := time.NewTicker(cronTickInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.tick(); err != nil {
+			m.app.logger.Errorf("cron: %v", err)
+		}
+	}
+	return nil
+}
+
+func (m *cronManager) tick() error {
+	due, err := m.app.cronRepository.Due(time.Now())
+	if err != nil {
+		return err
+	}
+	for _, c := range due {
+		if err := m.fire(c); err != nil {
+			m.app.logger.Errorf("cron: error triggering build for repo %d: %v", c.RepositoryID, err)
+		}
+	}
+	return nil
+}
+
+// fire triggers c's build and reschedules it regardless of whether the
+// trigger succeeded: a transient failure (SCM timeout, revoked token,
+// deleted repo) must still advance NextRun, or Due would keep handing
+// back the same entry on every tick and retry-storm instead of waiting
+// for its real next scheduled time. A malformed Spec is the one error
+// worth surfacing over the trigger's own, since rescheduling can't
+// recover from it either.
+func (m *cronManager) fire(c model.Cron) error {
+	triggerErr := m.app.TriggerBuild(TriggerBuildOptions{
+		RepoID: c.RepositoryID,
+		Event:  model.EventCron,
+		Branch: c.Branch,
+	})
+	if err := m.reschedule(c); err != nil {
+		return err
+	}
+	return triggerErr
+}
+
+func (m *cronManager) reschedule(c model.Cron) error {
+	schedule, err := cronParser.Parse(c.Spec)
+	if err != nil {
+		return err
+	}
+	next := schedule.Next(time.Now())
+	c.NextRun = &next
+	_, err = m.app.cronRepository.Update(c)
+	return err
+}
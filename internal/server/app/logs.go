@@ -0,0 +1,51 @@
+package app
+
+import (
+	"github.com/jkuri/abstruse/internal/pkg/logger"
+	"github.com/jkuri/abstruse/internal/server/secret"
+)
+
+// logBroadcastWriter adapts the per-job websocket broadcast into an
+// io.Writer so it can be wrapped by a logger.LineWriter.
+type logBroadcastWriter struct {
+	app   *App
+	jobID uint
+}
+
+func (w *logBroadcastWriter) Write(p []byte) (int, error) {
+	if err := w.app.ws.Broadcast("log", struct {
+		JobID uint   `json:"job_id"`
+		Line  string `json:"line"`
+	}{JobID: w.jobID, Line: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// HandleJobLog receives one chunk of log output streamed from the
+// worker running jobID, masks any secret value registered for that
+// job's repo/event/image, and broadcasts the result. Masking happens
+// here, before the line ever reaches persistence or a client, so
+// secrets injected into e.g. pull_request jobs from forks never leak
+// through job output.
+func (app *App) HandleJobLog(jobID uint, line []byte) error {
+	job, err := app.jobRepository.Find(jobID)
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	if app.secretRepository != nil {
+		secrets, err := app.secretRepository.FindForRepo(job.Build.RepositoryID)
+		if err != nil {
+			return err
+		}
+		for _, s := range secret.Match(secrets, job.Build.Event, job.Image) {
+			values = append(values, s.Value)
+		}
+	}
+
+	w := logger.NewLineWriter(&logBroadcastWriter{app: app, jobID: jobID}, values...)
+	_, err = w.Write(line)
+	return err
+}
@@ -0,0 +1,360 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jkuri/abstruse/internal/core"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+const (
+	// jobLeaseTTL is how long a worker's lease on a running job may go
+	// unrenewed before the server considers the worker lost.
+	jobLeaseTTL = 90 // seconds
+
+	// maxJobRetries bounds how many times a job lost to a dead worker
+	// is rescheduled before it is left ended and not run again.
+	maxJobRetries = 2
+
+	jobLeasePrefix = "/abstruse/jobs/running/"
+)
+
+// Base priorities per triggering event, highest first. A repository's
+// configured Weight is added on top so one repo can be biased above
+// another without changing event semantics.
+const (
+	priorityDeploy      uint16 = 500
+	priorityManual      uint16 = 400
+	priorityPush        uint16 = 300
+	priorityPullRequest uint16 = 200
+	priorityCron        uint16 = 100
+	priorityDefault     uint16 = 150
+)
+
+// eventPriority returns the base scheduling priority for a build event.
+func eventPriority(event string) uint16 {
+	switch event {
+	case "deploy":
+		return priorityDeploy
+	case "manual":
+		return priorityManual
+	case "push":
+		return priorityPush
+	case "pull_request":
+		return priorityPullRequest
+	case "cron":
+		return priorityCron
+	default:
+		return priorityDefault
+	}
+}
+
+// maxRepoWeight bounds how much a repo's configured Weight may bias
+// its priority, so it can't swamp the event ordering above.
+const maxRepoWeight = 99
+
+// jobPriority combines a build event's base priority with a repo's
+// configured weight. weight is clamped to [0, maxRepoWeight] first -
+// converting a negative weight straight to uint16 would wrap around to
+// a huge value under Go's conversion rules, deprioritizing exactly
+// backwards - and the sum is capped at uint16's max instead of
+// overflowing back down to a small value.
+func jobPriority(event string, weight int) uint16 {
+	if weight < 0 {
+		weight = 0
+	}
+	if weight > maxRepoWeight {
+		weight = maxRepoWeight
+	}
+	sum := int(eventPriority(event)) + weight
+	if sum > math.MaxUint16 {
+		sum = math.MaxUint16
+	}
+	return uint16(sum)
+}
+
+// queueKey identifies one fair-share queue: a single user's jobs
+// within a single repo. Keying on the pair (rather than just RepoID)
+// means a repo with many users, or a user pushing to many repos,
+// can't starve its neighbors in either dimension.
+type queueKey struct {
+	RepoID uint
+	UserID uint
+}
+
+// scheduler assigns jobs to connected workers through per-repo,
+// per-user fair-share queues, and watches the job leases workers hold
+// in etcd while a job runs, so a worker crash or network partition
+// doesn't leave a job stuck in StatusRunning forever.
+type scheduler struct {
+	client  *clientv3.Client
+	logger  *zap.SugaredLogger
+	app     *App
+	retries map[uint]int
+
+	mu     sync.Mutex
+	queues map[queueKey][]*core.Job
+	keys   []queueKey // round-robin order of queues with pending jobs
+	cursor int
+}
+
+func newScheduler(client *clientv3.Client, logger *zap.Logger, app *App) *scheduler {
+	return &scheduler{
+		client:  client,
+		logger:  logger.With(zap.String("type", "scheduler")).Sugar(),
+		app:     app,
+		retries: make(map[uint]int),
+		queues:  make(map[queueKey][]*core.Job),
+	}
+}
+
+// run watches job leases until the watch fails. It does not dispatch
+// queued jobs itself; they are dispatched when Schedule is called.
+func (s *scheduler) run() error {
+	return s.watchLeases(context.Background())
+}
+
+// Schedule enqueues a job onto its repo/user fair-share queue and
+// immediately attempts to dispatch it to a worker.
+func (s *scheduler) Schedule(j *core.Job) {
+	j.Status = core.StatusQueued
+	s.enqueue(j)
+	s.dispatch()
+}
+
+// Cancel stops the job if running, or removes it from its queue.
+func (s *scheduler) Cancel(jobID uint) error {
+	if s.dequeue(jobID) {
+		return nil
+	}
+	s.app.mu.RLock()
+	defer s.app.mu.RUnlock()
+	for _, w := range s.app.Workers {
+		if err := w.cancel(jobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *scheduler) enqueue(j *core.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := queueKey{RepoID: j.RepoID, UserID: j.UserID}
+	if _, ok := s.queues[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	q := append(s.queues[key], j)
+	sort.SliceStable(q, func(a, b int) bool { return q[a].Priority > q[b].Priority })
+	s.queues[key] = q
+}
+
+// dequeue removes jobID from whichever queue it is waiting in. It
+// reports whether the job was found queued (as opposed to running).
+func (s *scheduler) dequeue(jobID uint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, q := range s.queues {
+		for i, j := range q {
+			if j.ID != jobID {
+				continue
+			}
+			s.queues[key] = append(q[:i], q[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch walks queues round-robin, so one busy repo or user can't
+// starve another, and assigns each queue's highest-priority pending
+// job to the best-fit available worker.
+func (s *scheduler) dispatch() {
+	s.app.mu.RLock()
+	workers := make([]*worker, 0, len(s.app.Workers))
+	for _, w := range s.app.Workers {
+		workers = append(workers, w)
+	}
+	s.app.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assignedAny := true
+	for assignedAny {
+		assignedAny = false
+		for i := 0; i < len(s.keys); i++ {
+			idx := (s.cursor + i) % len(s.keys)
+			key := s.keys[idx]
+			q := s.queues[key]
+			if len(q) == 0 {
+				continue
+			}
+			w := bestFit(workers, q[0])
+			if w == nil {
+				continue
+			}
+			w.client = s.client
+			w.run(q[0])
+			s.queues[key] = q[1:]
+			s.cursor = (idx + 1) % len(s.keys)
+			assignedAny = true
+			break
+		}
+	}
+
+	// drop queues that ran dry so the round-robin order doesn't grow
+	// without bound.
+	live := s.keys[:0]
+	for _, key := range s.keys {
+		if len(s.queues[key]) > 0 {
+			live = append(live, key)
+		} else {
+			delete(s.queues, key)
+		}
+	}
+	s.keys = live
+
+	if err := s.app.broadcastQueueState(s.stateLocked()); err != nil {
+		s.logger.Errorf("scheduler: error broadcasting queue state: %v", err)
+	}
+}
+
+// stateLocked is State without re-acquiring s.mu, for callers (like
+// dispatch) that already hold it.
+func (s *scheduler) stateLocked() []QueueState {
+	state := make([]QueueState, 0, len(s.keys))
+	for _, key := range s.keys {
+		q := s.queues[key]
+		state = append(state, QueueState{
+			RepoID:       key.RepoID,
+			UserID:       key.UserID,
+			Pending:      len(q),
+			EstimatedAvg: len(q) * 60,
+		})
+	}
+	return state
+}
+
+// bestFit returns the worker with the most remaining capacity that can
+// still fit j, or nil if none can.
+func bestFit(workers []*worker, j *core.Job) *worker {
+	var best *worker
+	var bestScore int64 = -1
+	for _, w := range workers {
+		if !w.fits(j) {
+			continue
+		}
+		cpu, mem := w.remaining()
+		score := cpu + mem
+		if best == nil || score > bestScore {
+			best, bestScore = w, score
+		}
+	}
+	return best
+}
+
+// QueueState describes one repo/user queue's pending jobs, for the
+// UI's live queue view.
+type QueueState struct {
+	RepoID       uint `json:"repo_id"`
+	UserID       uint `json:"user_id"`
+	Pending      int  `json:"pending"`
+	EstimatedAvg int  `json:"estimated_wait_seconds"`
+}
+
+// State returns a snapshot of all pending per-repo/per-user queues.
+func (s *scheduler) State() []QueueState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stateLocked()
+}
+
+// watchLeases watches job lease keys for deletions (expiry without
+// renewal) and fails over any job that has no EndTime yet when its
+// lease disappears.
+func (s *scheduler) watchLeases(ctx context.Context) error {
+	watch := s.client.Watch(ctx, jobLeasePrefix, clientv3.WithPrefix())
+	for resp := range watch {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			if ev.Type != clientv3.EventTypeDelete {
+				continue
+			}
+			jobID, err := jobIDFromLeaseKey(string(ev.Kv.Key))
+			if err != nil {
+				s.logger.Errorf("scheduler: %v", err)
+				continue
+			}
+			if err := s.handleLostJob(jobID); err != nil {
+				s.logger.Errorf("scheduler: error handling lost job %d: %v", jobID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// handleLostJob records a job whose lease expired while still running
+// as ended (it sets EndTime; the job record carries no status or
+// failure reason), releases the worker's capacity, finalizes the parent
+// build, and reschedules the job if it still has retry budget.
+func (s *scheduler) handleLostJob(jobID uint) error {
+	job, err := s.app.jobRepository.Find(jobID)
+	if err != nil {
+		return err
+	}
+	if job.EndTime != nil {
+		return nil // already finished before the lease expired
+	}
+
+	// The lease is already gone, but the worker that held it may not
+	// know that yet (e.g. it was a transient network partition rather
+	// than the worker dying) - release its reserved capacity and
+	// jobs-map entry the same way an explicit Cancel would, so this
+	// doesn't leak a capacity slot on every lease-expiry failover.
+	s.app.mu.RLock()
+	for _, w := range s.app.Workers {
+		if err := w.cancel(jobID); err != nil {
+			s.logger.Errorf("scheduler: error releasing worker capacity for lost job %d: %v", jobID, err)
+		}
+	}
+	s.app.mu.RUnlock()
+
+	now := time.Now()
+	job.EndTime = &now
+	if _, err := s.app.jobRepository.Update(job); err != nil {
+		return err
+	}
+	if err := s.app.updateBuildTime(job.BuildID); err != nil {
+		return err
+	}
+
+	s.retries[jobID]++
+	if s.retries[jobID] > maxJobRetries {
+		delete(s.retries, jobID)
+		return nil
+	}
+	// Clear the EndTime set above so a later lease expiry of the
+	// rescheduled run is not taken for an already finished job.
+	job.EndTime = nil
+	if _, err := s.app.jobRepository.Update(job); err != nil {
+		return err
+	}
+	return s.app.scheduleJob(job, job.Build.Repository, job.Build.Commit, job.Build.Event, job.Build.UserID)
+}
+
+func jobIDFromLeaseKey(key string) (uint, error) {
+	var id uint
+	if _, err := fmt.Sscanf(key, jobLeasePrefix+"%d", &id); err != nil {
+		return 0, fmt.Errorf("invalid job lease key %q: %w", key, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/jkuri/abstruse/internal/core"
+)
+
+func TestEventPriority(t *testing.T) {
+	tests := []struct {
+		event string
+		want  uint16
+	}{
+		{"deploy", priorityDeploy},
+		{"manual", priorityManual},
+		{"push", priorityPush},
+		{"pull_request", priorityPullRequest},
+		{"cron", priorityCron},
+		{"unknown", priorityDefault},
+	}
+	for _, tt := range tests {
+		if got := eventPriority(tt.event); got != tt.want {
+			t.Errorf("eventPriority(%q) = %d, want %d", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestJobPriorityClampsWeight(t *testing.T) {
+	if got, want := jobPriority("push", -10), priorityPush; got != want {
+		t.Errorf("jobPriority(push, -10) = %d, want %d (negative weight clamped to 0)", got, want)
+	}
+	if got, want := jobPriority("push", maxRepoWeight*10), priorityPush+maxRepoWeight; got != want {
+		t.Errorf("jobPriority(push, huge) = %d, want %d (weight clamped to maxRepoWeight)", got, want)
+	}
+	if got, want := jobPriority("push", 50), priorityPush+50; got != want {
+		t.Errorf("jobPriority(push, 50) = %d, want %d", got, want)
+	}
+}
+
+func TestBestFitPicksWorkerWithMostRemainingCapacity(t *testing.T) {
+	j := &core.Job{CPU: 100, Memory: 100}
+	tight := &worker{id: "tight", max: 1, cpu: 200, memory: 200, usedCPU: 150, usedMemory: 150}
+	roomy := &worker{id: "roomy", max: 1, cpu: 1000, memory: 1000}
+	full := &worker{id: "full", max: 1, running: 1, cpu: 1000, memory: 1000}
+
+	got := bestFit([]*worker{tight, roomy, full}, j)
+	if got == nil || got.id != "roomy" {
+		t.Errorf("bestFit() = %v, want roomy", got)
+	}
+}
+
+func TestBestFitReturnsNilWhenNoneFit(t *testing.T) {
+	j := &core.Job{CPU: 500, Memory: 500}
+	w := &worker{id: "small", max: 1, cpu: 100, memory: 100}
+
+	if got := bestFit([]*worker{w}, j); got != nil {
+		t.Errorf("bestFit() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,13 @@
+package app
+
+// broadcastNewBuild notifies connected UI clients that a new build
+// was created.
+func (app *App) broadcastNewBuild(buildID uint) error {
+	return app.ws.Broadcast("build", buildID)
+}
+
+// broadcastQueueState pushes the current per-repo queue snapshot to
+// every connected UI client, so the queue view updates live.
+func (app *App) broadcastQueueState(state []QueueState) error {
+	return app.ws.Broadcast("queue", state)
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jkuri/abstruse/internal/core"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// leaseRenewInterval is how often a running job's lease is extended,
+// well inside jobLeaseTTL so a single missed tick doesn't expire it.
+const leaseRenewInterval = 30 * time.Second
+
+// jobLease tracks the etcd lease backing a single running job, plus
+// the cancel func that stops its renewal goroutine.
+type jobLease struct {
+	job     *core.Job
+	leaseID clientv3.LeaseID
+	cancel  context.CancelFunc
+}
+
+// worker represents single connected worker and how much job capacity
+// it currently has in use.
+type worker struct {
+	id      string
+	client  *clientv3.Client
+	max     int32
+	running int32
+
+	// cpu and memory are advertised at registration time (from the
+	// worker's etcd registration payload); usedCPU/usedMemory track
+	// what's currently reserved by running jobs.
+	cpu        int64
+	memory     int64
+	usedCPU    int64
+	usedMemory int64
+
+	mu   sync.Mutex
+	jobs map[uint]*jobLease
+}
+
+// jobLeaseKey is the etcd key a job's lease is registered under while
+// it runs; watchLeases watches this prefix for deletions.
+func jobLeaseKey(jobID uint) string {
+	return fmt.Sprintf("%s%d", jobLeasePrefix, jobID)
+}
+
+// fits reports whether the worker has both free capacity slots and
+// enough remaining CPU/Memory to run j.
+func (w *worker) fits(j *core.Job) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running >= w.max {
+		return false
+	}
+	if j.CPU > 0 && w.cpu > 0 && w.usedCPU+j.CPU > w.cpu {
+		return false
+	}
+	if j.Memory > 0 && w.memory > 0 && w.usedMemory+j.Memory > w.memory {
+		return false
+	}
+	return true
+}
+
+// remaining returns the worker's unreserved CPU and Memory, used to
+// rank workers by best fit.
+func (w *worker) remaining() (cpu, memory int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cpu - w.usedCPU, w.memory - w.usedMemory
+}
+
+// run dispatches j to this worker, accounts for the used capacity,
+// registers a jobLeaseTTL-second lease for it in etcd under
+// jobLeaseKey(j.ID), and starts a goroutine that extends that lease
+// every leaseRenewInterval for as long as the job runs. The renewal
+// goroutine runs on the server; when the worker's registration
+// disappears, unregisterWorker stops it, the lease expires, and
+// watchLeases picks up the resulting delete event.
+func (w *worker) run(j *core.Job) {
+	w.mu.Lock()
+	if w.jobs == nil {
+		w.jobs = make(map[uint]*jobLease)
+	}
+	j.Status = core.StatusRunning
+	w.running++
+	w.usedCPU += j.CPU
+	w.usedMemory += j.Memory
+	w.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lease := &jobLease{job: j, cancel: cancel}
+
+	if w.client != nil {
+		if grant, err := w.client.Grant(ctx, jobLeaseTTL); err == nil {
+			if _, err := w.client.Put(ctx, jobLeaseKey(j.ID), w.id, clientv3.WithLease(grant.ID)); err == nil {
+				lease.leaseID = grant.ID
+				go w.extend(ctx, grant.ID)
+			}
+		}
+	}
+
+	w.mu.Lock()
+	w.jobs[j.ID] = lease
+	w.mu.Unlock()
+}
+
+// extend calls KeepAliveOnce on leaseID every leaseRenewInterval,
+// mirroring the ticker a worker runs for the life of a job (Woodpecker's
+// `client.Extend(ctx, work.ID)`), until ctx is canceled by cancel or
+// unregisterWorker.
+func (w *worker) extend(ctx context.Context, leaseID clientv3.LeaseID) {
+	ticker := time.NewTicker(leaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.client.KeepAliveOnce(ctx, leaseID); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// cancel stops jobID on this worker, if assigned to it: it stops the
+// lease renewal goroutine, revokes the lease so the delete event fires
+// immediately instead of waiting out jobLeaseTTL, and releases capacity.
+func (w *worker) cancel(jobID uint) error {
+	w.mu.Lock()
+	lease, ok := w.jobs[jobID]
+	if !ok {
+		w.mu.Unlock()
+		return nil
+	}
+	delete(w.jobs, jobID)
+	if w.running > 0 {
+		w.running--
+	}
+	w.usedCPU -= lease.job.CPU
+	w.usedMemory -= lease.job.Memory
+	w.mu.Unlock()
+
+	lease.job.Status = core.StatusCanceled
+	lease.cancel()
+	if w.client != nil && lease.leaseID != 0 {
+		if _, err := w.client.Revoke(context.Background(), lease.leaseID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *worker) String() string {
+	return fmt.Sprintf("worker(%s)", w.id)
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.etcd.io/etcd/clientv3"
+)
+
+// workerPrefix is the etcd prefix workers register themselves under,
+// keyed by worker ID, with a JSON workerRegistration as the value.
+const workerPrefix = "/abstruse/workers/"
+
+// workerRegistration is what a worker advertises about itself at
+// registration time: how many jobs it can run concurrently, and how
+// much CPU/Memory it has, so the scheduler can pick a best-fit worker
+// instead of only checking "has a free slot".
+type workerRegistration struct {
+	ID     string `json:"id"`
+	Max    int32  `json:"max"`
+	CPU    int64  `json:"cpu"`
+	Memory int64  `json:"memory"`
+}
+
+// watchWorkers seeds app.Workers from whatever is currently registered
+// in etcd, then keeps it in sync as workers register or their
+// registration key expires/is removed.
+func (app *App) watchWorkers() error {
+	ctx := context.Background()
+	resp, err := app.client.Get(ctx, workerPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	for _, kv := range resp.Kvs {
+		app.registerWorker(kv.Value)
+	}
+
+	watch := app.client.Watch(ctx, workerPrefix, clientv3.WithPrefix())
+	for resp := range watch {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range resp.Events {
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				app.registerWorker(ev.Kv.Value)
+			case clientv3.EventTypeDelete:
+				app.unregisterWorker(strings.TrimPrefix(string(ev.Kv.Key), workerPrefix))
+			}
+		}
+	}
+	return nil
+}
+
+// registerWorker upserts app.Workers from a worker's etcd registration
+// payload, so its advertised Max/CPU/Memory stay current across
+// re-registrations (e.g. after the worker restarts).
+func (app *App) registerWorker(data []byte) {
+	var reg workerRegistration
+	if err := jsoniter.Unmarshal(data, &reg); err != nil {
+		app.logger.Errorf("worker: invalid registration payload: %v", err)
+		return
+	}
+
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	w, ok := app.Workers[reg.ID]
+	if !ok {
+		w = &worker{id: reg.ID}
+		app.Workers[reg.ID] = w
+	}
+	w.max = reg.Max
+	w.cpu = reg.CPU
+	w.memory = reg.Memory
+}
+
+func (app *App) unregisterWorker(id string) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if w, ok := app.Workers[id]; ok {
+		// stop renewing the worker's job leases so they expire and
+		// watchLeases fails its jobs over.
+		w.mu.Lock()
+		for _, lease := range w.jobs {
+			lease.cancel()
+		}
+		w.mu.Unlock()
+	}
+	delete(app.Workers, id)
+}
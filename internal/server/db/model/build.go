@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Build event types. A build is always associated with exactly one
+// event describing what triggered it.
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventTag         = "tag"
+	EventDeploy      = "deploy"
+	EventManual      = "manual"
+	EventCron        = "cron"
+)
+
+// Build represents single repository build, the top-level entity jobs
+// belong to. Builds are addressable per-repository via Number, e.g.
+// repo/#42.
+type Build struct {
+	ID              uint        `json:"id" gorm:"primary_key"`
+	Number          uint        `json:"number" gorm:"not null"`
+	Event           string      `json:"event" gorm:"not null"`
+	Branch          string      `json:"branch"`
+	Commit          string      `json:"commit"`
+	CommitMessage   string      `json:"commit_message"`
+	Config          string      `json:"config"`
+	AuthorLogin     string      `json:"author_login"`
+	AuthorName      string      `json:"author_name"`
+	AuthorEmail     string      `json:"author_email"`
+	AuthorAvatar    string      `json:"author_avatar"`
+	CommitterLogin  string      `json:"committer_login"`
+	CommitterName   string      `json:"committer_name"`
+	CommitterEmail  string      `json:"committer_email"`
+	CommitterAvatar string      `json:"committer_avatar"`
+	RepositoryID    uint        `json:"repository_id"`
+	Repository      *Repository `json:"repository,omitempty"`
+	// UserID is who triggered the build (manual/deploy); zero for
+	// webhook- and cron-triggered builds, which have no acting user.
+	// Used as the fair-share key alongside RepositoryID so one user
+	// can't starve another within a busy repo.
+	UserID uint   `json:"user_id"`
+	Jobs   []*Job `json:"jobs,omitempty"`
+	// ParentID references the build this one was restarted from, so
+	// restart lineage is queryable. Nil for builds triggered directly.
+	ParentID  *uint      `json:"parent_id,omitempty"`
+	Parent    *Build     `json:"parent,omitempty"`
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Cron schedules a recurring build for a repository/branch. Spec is a
+// standard cron expression (`@daily`, `@hourly`, or a 5/6-field
+// expression) parsed with robfig/cron. NextRun is recomputed and
+// persisted after each firing so schedules survive restarts.
+type Cron struct {
+	ID           uint        `json:"id" gorm:"primary_key"`
+	RepositoryID uint        `json:"repository_id"`
+	Repository   *Repository `json:"repository,omitempty"`
+	Branch       string      `json:"branch"`
+	Spec         string      `json:"spec"`
+	NextRun      *time.Time  `json:"next_run"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Job represents single unit of work scheduled to a worker as part of
+// a build's job matrix.
+type Job struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	BuildID   uint       `json:"build_id"`
+	Build     *Build     `json:"build,omitempty"`
+	Image     string     `json:"image"`
+	Commands  string     `json:"commands"`
+	Env       []string   `json:"env" gorm:"type:text[]"`
+	CPU       int64      `json:"cpu"`
+	Memory    int64      `json:"memory"`
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+}
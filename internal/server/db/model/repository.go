@@ -0,0 +1,24 @@
+package model
+
+// Provider holds the SCM provider configuration (GitHub, GitLab,
+// Bitbucket, Gitea...) a repository is connected through.
+type Provider struct {
+	ID          uint   `json:"id" gorm:"primary_key"`
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	AccessToken string `json:"access_token"`
+}
+
+// Repository represents single repository registered in abstruse.
+type Repository struct {
+	ID            uint      `json:"id" gorm:"primary_key"`
+	FullName      string    `json:"full_name"`
+	URL           string    `json:"url"`
+	DefaultBranch string    `json:"default_branch"`
+	ProviderID    uint      `json:"provider_id"`
+	Provider      *Provider `json:"provider,omitempty"`
+	// Weight biases scheduling priority for this repository's jobs
+	// relative to others, on top of the event-derived base priority.
+	// Defaults to 0 (no bias) when unset.
+	Weight int `json:"weight"`
+}
@@ -0,0 +1,15 @@
+package model
+
+// Secret is a named value injected into job environments as an
+// environment variable. A nil RepositoryID scopes the secret to the
+// whole organization; otherwise it is only available to that one
+// repository. Events and Images restrict which jobs the secret may be
+// attached to, which matters most for pull requests coming from forks.
+type Secret struct {
+	ID           uint     `json:"id" gorm:"primary_key"`
+	Name         string   `json:"name"`
+	Value        string   `json:"-"`
+	RepositoryID *uint    `json:"repository_id,omitempty"`
+	Events       []string `json:"events" gorm:"type:text[]"`
+	Images       []string `json:"images" gorm:"type:text[]"`
+}
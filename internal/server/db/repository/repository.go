@@ -0,0 +1,50 @@
+// Package repository defines the persistence interfaces used by the
+// server app. Concrete implementations live alongside the configured
+// database driver.
+package repository
+
+import (
+	"time"
+
+	"github.com/jkuri/abstruse/internal/server/db/model"
+)
+
+// BuildRepository persists and queries model.Build entities.
+type BuildRepository interface {
+	// Create inserts a new build. Implementations must assign Number
+	// atomically (e.g. `SELECT MAX(number) FOR UPDATE` within the same
+	// transaction, or a dedicated per-repo counter row) so that two
+	// concurrent builds for the same repository never collide.
+	Create(build model.Build) (model.Build, error)
+	Find(id uint) (model.Build, error)
+	FindAll(id uint) (model.Build, error)
+	Update(build model.Build) (model.Build, error)
+}
+
+// JobRepository persists and queries model.Job entities.
+type JobRepository interface {
+	Create(job *model.Job) (*model.Job, error)
+	Find(id uint) (*model.Job, error)
+	Update(job *model.Job) (*model.Job, error)
+}
+
+// RepoRepository persists and queries model.Repository entities.
+type RepoRepository interface {
+	Find(id, userID uint) (*model.Repository, error)
+}
+
+// CronRepository persists and queries model.Cron entities.
+//
+// This is the full CRUD surface for cron entries for now. There is no
+// HTTP router or handler package anywhere in this codebase yet to hang
+// REST routes on, so exposing cron management over HTTP is left as a
+// follow-up once that layer lands rather than bolted on ad hoc here.
+type CronRepository interface {
+	Create(cron model.Cron) (model.Cron, error)
+	Find(id uint) (model.Cron, error)
+	FindAll() ([]model.Cron, error)
+	// Due returns every cron entry whose NextRun is at or before now.
+	Due(now time.Time) ([]model.Cron, error)
+	Update(cron model.Cron) (model.Cron, error)
+	Delete(id uint) error
+}
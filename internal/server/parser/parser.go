@@ -0,0 +1,188 @@
+// Package parser parses the `.abstruse.yml` pipeline configuration
+// file into the job matrix the server schedules.
+package parser
+
+import (
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the parsed shape of `.abstruse.yml`.
+type Config struct {
+	Image     string    `yaml:"image"`
+	Resources Resources `yaml:"resources"`
+	When      When      `yaml:"when"`
+	Matrix    []Entry   `yaml:"matrix"`
+}
+
+// Resources declares the CPU/Memory a job needs, used by the scheduler
+// to pick a worker with enough remaining capacity. Zero means "no
+// preference".
+type Resources struct {
+	CPU    int64 `yaml:"cpu"`
+	Memory int64 `yaml:"memory"`
+}
+
+// Entry is one job matrix entry. Its Env values become the environment
+// of the job created for it.
+type Entry struct {
+	Env  []string `yaml:"env"`
+	When When     `yaml:"when"`
+}
+
+// Filter is an include/exclude glob list. An empty Include matches
+// everything; a non-empty Exclude always wins over Include.
+type Filter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Match reports whether val matches f's include/exclude globs.
+func (f Filter) Match(val string) bool {
+	for _, pattern := range f.Exclude {
+		if matchGlob(pattern, val) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if matchGlob(pattern, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether val matches pattern, where pattern may use
+// `**` to match zero or more path segments in addition to the usual
+// single-segment `*`/`?` supported by path.Match. path.Match alone
+// can't express "any depth below here" (`**` would just be treated as
+// two literal `*`, neither of which crosses a `/`), which is the common
+// case for `when: path` filters like `internal/**`.
+func matchGlob(pattern, val string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(val, "/"))
+}
+
+// matchSegments matches path segments one at a time, letting a "**"
+// segment consume any number (including zero) of val segments.
+func matchSegments(pattern, val []string) bool {
+	if len(pattern) == 0 {
+		return len(val) == 0
+	}
+	if pattern[0] == "**" {
+		if matchSegments(pattern[1:], val) {
+			return true
+		}
+		if len(val) == 0 {
+			return false
+		}
+		return matchSegments(pattern, val[1:])
+	}
+	if len(val) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], val[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], val[1:])
+}
+
+// MatchAny reports whether f matches at least one of vals. Used for
+// path filters, where a job runs if any changed file matches.
+func (f Filter) MatchAny(vals []string) bool {
+	if len(vals) == 0 {
+		return true // no diff available, fail open
+	}
+	for _, v := range vals {
+		if f.Match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// When declares the conditions under which a job (or the whole build,
+// at the top level) runs: branch include/exclude globs, path
+// include/exclude globs, and an event allow-list.
+type When struct {
+	Branch Filter   `yaml:"branch"`
+	Path   Filter   `yaml:"path"`
+	Event  []string `yaml:"event"`
+}
+
+// Matches reports whether w allows a job to run for the given branch,
+// event and set of changed paths.
+func (w When) Matches(branch, event string, changedFiles []string) bool {
+	if !w.Branch.Match(branch) {
+		return false
+	}
+	if len(w.Event) > 0 {
+		allowed := false
+		for _, e := range w.Event {
+			if e == event {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	if !w.Path.MatchAny(changedFiles) {
+		return false
+	}
+	return true
+}
+
+// hasPathFilter reports whether w declares any path filter, so callers
+// know whether it's worth diffing the commit at all.
+func (w When) hasPathFilter() bool {
+	return len(w.Path.Include) > 0 || len(w.Path.Exclude) > 0
+}
+
+// ConfigParser parses the raw `.abstruse.yml` contents into Parsed.
+// Commands is a single command list shared by every matrix entry.
+type ConfigParser struct {
+	Raw      string
+	Parsed   Config
+	Commands []string
+}
+
+// Parse unmarshals Raw into Parsed. It does not set Commands.
+func (p *ConfigParser) Parse() error {
+	return yaml.Unmarshal([]byte(p.Raw), &p.Parsed)
+}
+
+// HasPathFilters reports whether any matrix entry (or the top-level
+// config) declares path filters, so TriggerBuild knows whether it
+// needs to diff the commit before scheduling.
+func (p *ConfigParser) HasPathFilters() bool {
+	if p.Parsed.When.hasPathFilter() {
+		return true
+	}
+	for _, e := range p.Parsed.Matrix {
+		if e.When.hasPathFilter() {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDirectives short-circuit TriggerBuild when present in a commit
+// message, mirroring Drone/Woodpecker's `[skip ci]` / `[ci skip]`.
+var skipDirectives = []string{"[skip ci]", "[ci skip]"}
+
+// HasSkipDirective reports whether msg contains a skip-ci directive.
+func HasSkipDirective(msg string) bool {
+	lower := strings.ToLower(msg)
+	for _, d := range skipDirectives {
+		if strings.Contains(lower, d) {
+			return true
+		}
+	}
+	return false
+}
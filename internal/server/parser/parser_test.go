@@ -0,0 +1,81 @@
+package parser
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		f    Filter
+		val  string
+		want bool
+	}{
+		{"empty filter matches anything", Filter{}, "main", true},
+		{"include match", Filter{Include: []string{"main", "release/*"}}, "release/1.0", true},
+		{"include no match", Filter{Include: []string{"main"}}, "develop", false},
+		{"exclude wins over include", Filter{Include: []string{"*"}, Exclude: []string{"draft/*"}}, "draft/wip", false},
+		{"exclude no match falls through to include", Filter{Exclude: []string{"draft/*"}}, "main", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Match(tt.val); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.val, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatchAny(t *testing.T) {
+	f := Filter{Include: []string{"src/*"}}
+	if !f.MatchAny(nil) {
+		t.Error("MatchAny with no changed files should fail open")
+	}
+	if !f.MatchAny([]string{"docs/readme.md", "src/main.go"}) {
+		t.Error("MatchAny should match when at least one path matches")
+	}
+	if f.MatchAny([]string{"docs/readme.md"}) {
+		t.Error("MatchAny should not match when no path matches")
+	}
+}
+
+func TestWhenMatches(t *testing.T) {
+	w := When{
+		Branch: Filter{Include: []string{"main"}},
+		Event:  []string{"push", "deploy"},
+	}
+	if !w.Matches("main", "push", nil) {
+		t.Error("expected branch+event to match")
+	}
+	if w.Matches("develop", "push", nil) {
+		t.Error("expected branch mismatch to be rejected")
+	}
+	if w.Matches("main", "pull_request", nil) {
+		t.Error("expected event not in allow-list to be rejected")
+	}
+}
+
+func TestWhenMatchesPathFilter(t *testing.T) {
+	w := When{Path: Filter{Include: []string{"internal/**", "go.mod"}}}
+	if !w.Matches("main", "push", []string{"internal/core/job.go"}) {
+		t.Error("expected matching changed path to pass")
+	}
+	if w.Matches("main", "push", []string{"docs/readme.md"}) {
+		t.Error("expected unrelated changed path to be rejected")
+	}
+}
+
+func TestHasSkipDirective(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"fix: typo [skip ci]", true},
+		{"fix: typo [CI SKIP]", true},
+		{"fix: typo [ci skip]", true},
+		{"fix: typo", false},
+	}
+	for _, tt := range tests {
+		if got := HasSkipDirective(tt.msg); got != tt.want {
+			t.Errorf("HasSkipDirective(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Package secret manages org- and repo-scoped secrets and decides
+// which ones a given job is allowed to see.
+package secret
+
+import "github.com/jkuri/abstruse/internal/server/db/model"
+
+// SecretRepository persists and queries model.Secret entities.
+type SecretRepository interface {
+	// FindForRepo returns every secret visible to repoID: repo-scoped
+	// secrets for that repository plus org-scoped secrets (those with
+	// a nil RepositoryID).
+	FindForRepo(repoID uint) ([]model.Secret, error)
+}
+
+// allowed reports whether val is present in list, or list is empty
+// (an empty allow-list means "all").
+func allowed(list []string, val string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// Match filters secrets down to the ones allowed for the given event
+// and image, per their Events/Images allow-lists.
+func Match(secrets []model.Secret, event, image string) []model.Secret {
+	matched := make([]model.Secret, 0, len(secrets))
+	for _, s := range secrets {
+		if !allowed(s.Events, event) || !allowed(s.Images, image) {
+			continue
+		}
+		matched = append(matched, s)
+	}
+	return matched
+}
+
+// Env renders secrets as KEY=VALUE environment variable strings.
+func Env(secrets []model.Secret) []string {
+	env := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		env = append(env, s.Name+"="+s.Value)
+	}
+	return env
+}
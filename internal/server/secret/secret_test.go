@@ -0,0 +1,58 @@
+package secret
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jkuri/abstruse/internal/server/db/model"
+)
+
+func TestMatch(t *testing.T) {
+	secrets := []model.Secret{
+		{Name: "ALL"},
+		{Name: "PUSH_ONLY", Events: []string{"push"}},
+		{Name: "DEPLOY_IMAGE", Events: []string{"deploy"}, Images: []string{"node:14"}},
+	}
+
+	got := Match(secrets, "push", "node:14")
+	var names []string
+	for _, s := range got {
+		names = append(names, s.Name)
+	}
+	want := []string{"ALL", "PUSH_ONLY"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Match(push, node:14) = %v, want %v", names, want)
+	}
+
+	got = Match(secrets, "deploy", "node:14")
+	names = nil
+	for _, s := range got {
+		names = append(names, s.Name)
+	}
+	want = []string{"ALL", "DEPLOY_IMAGE"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Match(deploy, node:14) = %v, want %v", names, want)
+	}
+
+	got = Match(secrets, "deploy", "python:3")
+	names = nil
+	for _, s := range got {
+		names = append(names, s.Name)
+	}
+	want = []string{"ALL"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Match(deploy, python:3) = %v, want %v", names, want)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	secrets := []model.Secret{
+		{Name: "FOO", Value: "bar"},
+		{Name: "BAZ", Value: "qux"},
+	}
+	got := Env(secrets)
+	want := []string{"FOO=bar", "BAZ=qux"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+}
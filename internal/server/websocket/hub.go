@@ -0,0 +1,26 @@
+package websocket
+
+import "sync"
+
+// hub tracks connected clients and fans out broadcast messages to
+// each of them.
+type hub struct {
+	mu      sync.RWMutex
+	clients map[chan []byte]struct{}
+}
+
+func newHub() *hub {
+	return &hub{clients: make(map[chan []byte]struct{})}
+}
+
+func (h *hub) broadcast(msg []byte) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients {
+		select {
+		case c <- msg:
+		default:
+			// slow client, drop the message rather than block the broadcaster
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package websocket fans out server-side events (new builds, queue
+// state, log lines) to connected UI clients.
+package websocket
+
+import (
+	"encoding/json"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// App holds the set of connected websocket clients and broadcasts
+// events to all of them.
+type App struct {
+	clients *hub
+}
+
+// NewApp returns a new websocket App.
+func NewApp() *App {
+	return &App{clients: newHub()}
+}
+
+// Broadcast marshals payload and sends it as a `{type, data}` message
+// to every connected client.
+func (a *App) Broadcast(eventType string, payload interface{}) error {
+	data, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg, err := jsoniter.Marshal(struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}{Type: eventType, Data: data})
+	if err != nil {
+		return err
+	}
+	a.clients.broadcast(msg)
+	return nil
+}